@@ -0,0 +1,223 @@
+package html // import "github.com/tdewolff/minify/html"
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/tdewolff/buffer"
+	"github.com/tdewolff/minify"
+	"github.com/tdewolff/parse"
+	"github.com/tdewolff/parse/html"
+)
+
+// attrWriter writes the attributes of a start tag, shared between Minify's
+// TokenBuffer-driven loop and StreamWriter's incremental one. The fields it
+// points into (rawTagMediatype and the three default mediatypes) are owned
+// by the caller, since they persist across tags for the lifetime of a single
+// document.
+type attrWriter struct {
+	o *Minifier
+	m minify.Minifier
+
+	rawTag                 *html.Hash
+	rawTagMediatype        *[]byte
+	defaultScriptType      *string
+	defaultStyleType       *string
+	defaultInlineStyleType *string
+
+	attrMinifyBuffer *buffer.Writer
+	attrByteBuffer   *[]byte
+}
+
+// applyInterdependentAttrs adjusts attrs in place for the small set of tags
+// whose minification depends on more than one attribute at once: <a>
+// (id/name de-duplication and href scheme handling gated by rel="external"),
+// <meta> (content-type normalization and charset/content-style-type/
+// content-script-type folding) and <script> (dropping a redundant charset
+// when src is set). An attribute removed this way has its Data set to nil;
+// writeAttr skips those.
+func (aw *attrWriter) applyInterdependentAttrs(tagHash html.Hash, attrs []Token) {
+	find := func(hash html.Hash) *Token {
+		for i := range attrs {
+			if attrs[i].Hash == hash {
+				if val := attrs[i].AttrVal; len(val) > 1 && (val[0] == '"' || val[0] == '\'') {
+					attrs[i].AttrVal = parse.Trim(val[1:len(val)-1], parse.IsWhitespace)
+				}
+				return &attrs[i]
+			}
+		}
+		return nil
+	}
+
+	switch tagHash {
+	case html.A:
+		id := find(html.Id)
+		name := find(html.Name)
+		if id != nil && name != nil && parse.Equal(id.AttrVal, name.AttrVal) {
+			name.Data = nil
+		}
+		rel := find(html.Rel)
+		if href := find(html.Href); href != nil && (rel == nil || !parse.EqualCaseInsensitive(rel.AttrVal, externalBytes)) {
+			if len(href.AttrVal) > 5 && parse.EqualCaseInsensitive(href.AttrVal[:5], dataBytes) {
+				href.AttrVal = minifyDataURI(aw.m, href.AttrVal)
+			} else if len(href.AttrVal) > 5 && parse.EqualCaseInsensitive(href.AttrVal[:4], httpBytes) {
+				if href.AttrVal[4] == ':' {
+					href.AttrVal = href.AttrVal[5:]
+				} else if (href.AttrVal[4] == 's' || href.AttrVal[4] == 'S') && href.AttrVal[5] == ':' {
+					href.AttrVal = href.AttrVal[6:]
+				}
+			}
+		}
+	case html.Meta:
+		content := find(html.Content)
+		if content != nil {
+			if httpEquiv := find(html.Http_Equiv); httpEquiv != nil {
+				content.AttrVal = parse.NormalizeContentType(content.AttrVal)
+				if charset := find(html.Charset); charset == nil && parse.EqualCaseInsensitive(httpEquiv.AttrVal, []byte("content-type")) && parse.Equal(content.AttrVal, []byte("text/html;charset=utf-8")) {
+					httpEquiv.Data = nil
+					content.Data = []byte("charset")
+					content.Hash = html.Charset
+					content.AttrVal = []byte("utf-8")
+				} else if parse.EqualCaseInsensitive(httpEquiv.AttrVal, []byte("content-style-type")) {
+					*aw.defaultStyleType = string(content.AttrVal)
+					*aw.defaultInlineStyleType = *aw.defaultStyleType + ";inline=1"
+				} else if parse.EqualCaseInsensitive(httpEquiv.AttrVal, []byte("content-script-type")) {
+					*aw.defaultScriptType = string(content.AttrVal)
+				}
+			}
+			if name := find(html.Name); name != nil {
+				if parse.EqualCaseInsensitive(name.AttrVal, []byte("keywords")) {
+					content.AttrVal = bytes.Replace(content.AttrVal, []byte(", "), []byte(","), -1)
+				} else if parse.EqualCaseInsensitive(name.AttrVal, []byte("viewport")) {
+					content.AttrVal = bytes.Replace(content.AttrVal, []byte(" "), []byte(""), -1)
+				}
+			}
+		}
+	case html.Script:
+		if src := find(html.Src); src != nil {
+			if charset := find(html.Charset); charset != nil {
+				charset.Data = nil
+			}
+		}
+	}
+}
+
+// writeAttr writes a single attribute (leading space, name, and an optional
+// ="value") for the start tag tagHash, applying empty-value omission,
+// case normalization, default-value omission, inline CSS/JS minification,
+// data: URI minification and http(s) scheme stripping for urlAttrMap attributes (except on <a>, whose
+// href was already handled by applyInterdependentAttrs), and quoting.
+func (aw *attrWriter) writeAttr(w io.Writer, tagHash html.Hash, attr Token) error {
+	if attr.Data == nil {
+		return nil // removed by applyInterdependentAttrs
+	}
+
+	val := attr.AttrVal
+	if len(val) > 1 && (val[0] == '"' || val[0] == '\'') {
+		val = parse.Trim(val[1:len(val)-1], parse.IsWhitespace)
+	}
+	if len(val) == 0 && (attr.Hash == html.Class ||
+		attr.Hash == html.Dir ||
+		attr.Hash == html.Id ||
+		attr.Hash == html.Lang ||
+		attr.Hash == html.Name ||
+		attr.Hash == html.Style ||
+		attr.Hash == html.Title ||
+		attr.Hash == html.Action && tagHash == html.Form ||
+		attr.Hash == html.Value && tagHash == html.Input ||
+		len(attr.Data) > 2 && attr.Data[0] == 'o' && attr.Data[1] == 'n') {
+		return nil // omit empty attribute values
+	}
+	if caseInsensitiveAttrMap[attr.Hash] {
+		val = parse.ToLower(val)
+		if attr.Hash == html.Enctype || attr.Hash == html.Codetype || attr.Hash == html.Accept || attr.Hash == html.Type && (tagHash == html.A || tagHash == html.Link || tagHash == html.Object || tagHash == html.Param || tagHash == html.Script || tagHash == html.Style || tagHash == html.Source) {
+			val = parse.NormalizeContentType(val)
+		}
+	}
+	if *aw.rawTag != 0 && attr.Hash == html.Type {
+		*aw.rawTagMediatype = val
+	}
+
+	// default attribute values can be ommited
+	if !aw.o.KeepDefaultAttrVals && (attr.Hash == html.Type && (tagHash == html.Script && parse.Equal(val, []byte("text/javascript")) ||
+		tagHash == html.Style && parse.Equal(val, []byte("text/css")) ||
+		tagHash == html.Link && parse.Equal(val, []byte("text/css")) ||
+		tagHash == html.Input && parse.Equal(val, []byte("text")) ||
+		tagHash == html.Button && parse.Equal(val, []byte("submit"))) ||
+		attr.Hash == html.Language && tagHash == html.Script ||
+		attr.Hash == html.Method && parse.Equal(val, []byte("get")) ||
+		attr.Hash == html.Enctype && parse.Equal(val, []byte("application/x-www-form-urlencoded")) ||
+		attr.Hash == html.Colspan && parse.Equal(val, []byte("1")) ||
+		attr.Hash == html.Rowspan && parse.Equal(val, []byte("1")) ||
+		attr.Hash == html.Shape && parse.Equal(val, []byte("rect")) ||
+		attr.Hash == html.Span && parse.Equal(val, []byte("1")) ||
+		attr.Hash == html.Clear && parse.Equal(val, []byte("none")) ||
+		attr.Hash == html.Frameborder && parse.Equal(val, []byte("1")) ||
+		attr.Hash == html.Scrolling && parse.Equal(val, []byte("auto")) ||
+		attr.Hash == html.Valuetype && parse.Equal(val, []byte("data")) ||
+		attr.Hash == html.Media && tagHash == html.Style && parse.Equal(val, []byte("all"))) {
+		return nil
+	}
+
+	if _, err := w.Write(spaceBytes); err != nil {
+		return err
+	}
+	if _, err := w.Write(attr.Data); err != nil {
+		return err
+	}
+
+	if booleanAttrMap[attr.Hash] {
+		if aw.o.XHTML {
+			if _, err := w.Write(isBytes); err != nil {
+				return err
+			}
+			if _, err := w.Write([]byte{'"'}); err != nil {
+				return err
+			}
+			if _, err := w.Write(attr.Data); err != nil {
+				return err
+			}
+			if _, err := w.Write([]byte{'"'}); err != nil {
+				return err
+			}
+		}
+		return nil
+	} else if len(val) == 0 {
+		return nil
+	}
+
+	if _, err := w.Write(isBytes); err != nil {
+		return err
+	}
+	// CSS and JS minifiers for attribute inline code
+	if attr.Hash == html.Style {
+		aw.attrMinifyBuffer.Reset()
+		if aw.m.Minify(*aw.defaultInlineStyleType, aw.attrMinifyBuffer, buffer.NewReader(val)) == nil {
+			val = aw.attrMinifyBuffer.Bytes()
+		}
+	} else if len(attr.Data) > 2 && attr.Data[0] == 'o' && attr.Data[1] == 'n' {
+		if len(val) >= 11 && parse.EqualCaseInsensitive(val[:11], []byte("javascript:")) {
+			val = val[11:]
+		}
+		aw.attrMinifyBuffer.Reset()
+		if aw.m.Minify(*aw.defaultScriptType, aw.attrMinifyBuffer, buffer.NewReader(val)) == nil {
+			val = aw.attrMinifyBuffer.Bytes()
+		}
+	} else if urlAttrMap[attr.Hash] && tagHash != html.A { // anchors' href is already handled
+		if len(val) > 5 && parse.EqualCaseInsensitive(val[:5], dataBytes) {
+			val = minifyDataURI(aw.m, val)
+		} else if len(val) > 5 && parse.EqualCaseInsensitive(val[:4], httpBytes) {
+			if val[4] == ':' {
+				val = val[5:]
+			} else if (val[4] == 's' || val[4] == 'S') && val[5] == ':' {
+				val = val[6:]
+			}
+		}
+	}
+	// no quotes if possible, else prefer single or double depending on which occurs more often in value
+	val = escapeAttrVal(aw.attrByteBuffer, attr.AttrVal, val, aw.o.KeepQuotes || aw.o.XHTML)
+	if _, err := w.Write(val); err != nil {
+		return err
+	}
+	return nil
+}
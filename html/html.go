@@ -2,6 +2,7 @@ package html // import "github.com/tdewolff/minify/html"
 
 import (
 	"bytes"
+	"encoding/base64"
 	"io"
 
 	"github.com/tdewolff/buffer"
@@ -20,13 +21,88 @@ var (
 	escapedDoubleQuoteBytes = []byte("&#34;")
 	externalBytes           = []byte("external")
 	httpBytes               = []byte("http")
+	dataBytes               = []byte("data:")
+	base64Bytes             = []byte(";base64,")
+	slashGtBytes            = []byte("/>")
+	scriptCDATAOpenBytes    = []byte("//<![CDATA[\n")
+	scriptCDATACloseBytes   = []byte("\n//]]>")
+	styleCDATAOpenBytes     = []byte("/*<![CDATA[*/")
+	styleCDATACloseBytes    = []byte("/*]]>*/")
 )
 
+// voidTagMap contains the HTML5 void elements, which have no end tag and are
+// self-closed (`<br/>`) in XHTML polyglot output.
+var voidTagMap = map[html.Hash]bool{
+	html.Area:   true,
+	html.Base:   true,
+	html.Br:     true,
+	html.Col:    true,
+	html.Embed:  true,
+	html.Hr:     true,
+	html.Img:    true,
+	html.Input:  true,
+	html.Link:   true,
+	html.Meta:   true,
+	html.Param:  true,
+	html.Source: true,
+	html.Track:  true,
+	html.Wbr:    true,
+}
+
+// dataURIUnreservedMap holds the bytes that may be written unescaped in the
+// percent-encoded form of a data URI payload.
+var dataURIUnreservedMap = [256]bool{}
+
+func init() {
+	for c := 'A'; c <= 'Z'; c++ {
+		dataURIUnreservedMap[c] = true
+	}
+	for c := 'a'; c <= 'z'; c++ {
+		dataURIUnreservedMap[c] = true
+	}
+	for c := '0'; c <= '9'; c++ {
+		dataURIUnreservedMap[c] = true
+	}
+	for _, c := range []byte("-_.!~*'()") {
+		dataURIUnreservedMap[c] = true
+	}
+}
+
 ////////////////////////////////////////////////////////////////
 
+// DefaultMinifier is the default minifier, used by the package-level Minify function.
+var DefaultMinifier = &Minifier{}
+
+// Minifier is the HTML minifier, it allows for configurable options.
+type Minifier struct {
+	KeepConditionalComments bool // preserve IE conditional comments; without it all comments are removed
+	KeepDefaultAttrVals     bool // do not omit attribute values that equal the tag's default, e.g. type="text/javascript" on <script>
+	KeepDocumentTags        bool // do not strip the <html>, <head> and <body> tags
+	KeepEndTags             bool // do not omit end tags whose omission is allowed by the HTML5 spec, e.g. </li>, </p>
+	KeepWhitespace          bool // do not collapse whitespace between tags, only within text
+	KeepQuotes              bool // always quote attribute values, even when quotes could safely be omitted
+	XHTML                   bool // emit a polyglot HTML5/XHTML serialization, implies KeepQuotes, KeepEndTags and KeepDocumentTags
+	MaxPeek                 int  // bound the lookahead used for whitespace trimming and optional end tag removal, to avoid buffering arbitrarily far ahead in large documents; 0 uses DefaultMaxPeek
+}
+
+// DefaultMaxPeek is the lookahead bound used when Minifier.MaxPeek is 0.
+const DefaultMaxPeek = 64
+
 // Minify minifies HTML5 files, it reads from r and writes to w.
 // Removes unnecessary whitespace, tags, attributes, quotes and comments and typically saves 10% in size.
 func Minify(m minify.Minifier, _ string, w io.Writer, r io.Reader) error {
+	return DefaultMinifier.Minify(m, w, r)
+}
+
+// Minify minifies HTML5 files, it reads from r and writes to w, using the options set on o.
+func (o *Minifier) Minify(m minify.Minifier, w io.Writer, r io.Reader) error {
+	keepDocumentTags := o.KeepDocumentTags || o.XHTML
+	keepEndTags := o.KeepEndTags || o.XHTML
+	maxPeek := o.MaxPeek
+	if maxPeek <= 0 {
+		maxPeek = DefaultMaxPeek
+	}
+
 	var rawTag html.Hash
 	var rawTagMediatype []byte
 	precededBySpace := true // on true the next text token must not start with a space
@@ -36,8 +112,13 @@ func Minify(m minify.Minifier, _ string, w io.Writer, r io.Reader) error {
 
 	attrMinifyBuffer := buffer.NewWriter(make([]byte, 0, 64))
 	attrByteBuffer := make([]byte, 0, 64)
-	attrIntBuffer := make([]int, 0, 4)
-	attrTokenBuffer := make([]*Token, 0, 4)
+
+	aw := &attrWriter{
+		o: o, m: m,
+		rawTag: &rawTag, rawTagMediatype: &rawTagMediatype,
+		defaultScriptType: &defaultScriptType, defaultStyleType: &defaultStyleType, defaultInlineStyleType: &defaultInlineStyleType,
+		attrMinifyBuffer: attrMinifyBuffer, attrByteBuffer: &attrByteBuffer,
+	}
 
 	z := html.NewTokenizer(r)
 	tb := NewTokenBuffer(z)
@@ -56,9 +137,9 @@ func Minify(m minify.Minifier, _ string, w io.Writer, r io.Reader) error {
 		case html.CommentToken:
 			// TODO: ensure that nested comments are handled properly (tokenizer doesn't handle this!)
 			var comment []byte
-			if bytes.HasPrefix(t.Data, []byte("[if")) {
+			if o.KeepConditionalComments && bytes.HasPrefix(t.Data, []byte("[if")) {
 				comment = append(append([]byte("<!--"), t.Data...), []byte("-->")...)
-			} else if bytes.HasSuffix(t.Data, []byte("--")) {
+			} else if o.KeepConditionalComments && bytes.HasSuffix(t.Data, []byte("--")) {
 				// only occurs when mixed up with conditional comments
 				comment = append(append([]byte("<!"), t.Data...), '>')
 			}
@@ -93,7 +174,26 @@ func Minify(m minify.Minifier, _ string, w io.Writer, r io.Reader) error {
 							t.Data = trimmedData[9:]
 						}
 					}
-					if err := m.Minify(mediatype, w, buffer.NewReader(t.Data)); err != nil {
+					if o.XHTML && (rawTag == html.Script || rawTag == html.Style) {
+						// wrap in a CDATA section so the raw text stays well-formed XML
+						cdataOpen, cdataClose := scriptCDATAOpenBytes, scriptCDATACloseBytes
+						if rawTag == html.Style {
+							cdataOpen, cdataClose = styleCDATAOpenBytes, styleCDATACloseBytes
+						}
+						if _, err := w.Write(cdataOpen); err != nil {
+							return err
+						}
+						if err := m.Minify(mediatype, w, buffer.NewReader(t.Data)); err != nil && err != minify.ErrNotExist {
+							return err
+						} else if err == minify.ErrNotExist {
+							if _, err := w.Write(t.Data); err != nil {
+								return err
+							}
+						}
+						if _, err := w.Write(cdataClose); err != nil {
+							return err
+						}
+					} else if err := m.Minify(mediatype, w, buffer.NewReader(t.Data)); err != nil {
 						if err == minify.ErrNotExist { // no minifier, write the original
 							if _, err := w.Write(t.Data); err != nil {
 								return err
@@ -105,43 +205,55 @@ func Minify(m minify.Minifier, _ string, w io.Writer, r io.Reader) error {
 				} else if _, err := w.Write(t.Data); err != nil {
 					return err
 				}
-			} else if t.Data = parse.ReplaceMultiple(t.Data, parse.IsWhitespace, ' '); len(t.Data) > 0 {
-				// whitespace removal; trim left
-				if precededBySpace && t.Data[0] == ' ' {
-					t.Data = t.Data[1:]
+			} else if o.KeepWhitespace && parse.IsAllWhitespace(t.Data) {
+				// whitespace between tags is written as is
+				if _, err := w.Write(t.Data); err != nil {
+					return err
 				}
+			} else if t.Data = parse.ReplaceMultiple(t.Data, parse.IsWhitespace, ' '); len(t.Data) > 0 {
+				if !o.KeepWhitespace {
+					// whitespace removal; trim left
+					if precededBySpace && t.Data[0] == ' ' {
+						t.Data = t.Data[1:]
+					}
 
-				// whitespace removal; trim right
-				precededBySpace = false
-				if len(t.Data) == 0 {
-					precededBySpace = true
-				} else if t.Data[len(t.Data)-1] == ' ' {
-					precededBySpace = true
-					trim := false
-					i := 0
-					for {
-						next := tb.Peek(i)
-						// trim if EOF, text token with whitespace begin or block token
-						if next.TokenType == html.ErrorToken {
-							trim = true
-							break
-						} else if next.TokenType == html.TextToken {
-							// remove if the text token starts with a whitespace
-							trim = (len(next.Data) > 0 && parse.IsWhitespace(next.Data[0]))
-							break
-						} else if next.TokenType == html.StartTagToken || next.TokenType == html.EndTagToken {
-							if !inlineTagMap[next.Hash] {
+					// whitespace removal; trim right
+					precededBySpace = false
+					if len(t.Data) == 0 {
+						precededBySpace = true
+					} else if t.Data[len(t.Data)-1] == ' ' {
+						precededBySpace = true
+						trim := false
+						i := 0
+						for {
+							if i >= maxPeek {
+								// give up and conservatively keep the trailing space rather than
+								// buffer arbitrarily far ahead looking for a decisive token
+								break
+							}
+							next := tb.Peek(i)
+							// trim if EOF, text token with whitespace begin or block token
+							if next.TokenType == html.ErrorToken {
 								trim = true
 								break
-							} else if next.TokenType == html.StartTagToken {
+							} else if next.TokenType == html.TextToken {
+								// remove if the text token starts with a whitespace
+								trim = (len(next.Data) > 0 && parse.IsWhitespace(next.Data[0]))
 								break
+							} else if next.TokenType == html.StartTagToken || next.TokenType == html.EndTagToken {
+								if !inlineTagMap[next.Hash] {
+									trim = true
+									break
+								} else if next.TokenType == html.StartTagToken {
+									break
+								}
 							}
+							i++
+						}
+						if trim {
+							t.Data = t.Data[:len(t.Data)-1]
+							precededBySpace = false
 						}
-						i++
-					}
-					if trim {
-						t.Data = t.Data[:len(t.Data)-1]
-						precededBySpace = false
 					}
 				}
 				if _, err := w.Write(t.Data); err != nil {
@@ -173,9 +285,11 @@ func Minify(m minify.Minifier, _ string, w io.Writer, r io.Reader) error {
 				}
 
 				// remove superfluous ending tags
-				if !hasAttributes && (t.Hash == html.Html || t.Hash == html.Head || t.Hash == html.Body || t.Hash == html.Colgroup) {
+				if !keepDocumentTags && !hasAttributes && (t.Hash == html.Html || t.Hash == html.Head || t.Hash == html.Body) {
+					break
+				} else if !keepEndTags && !hasAttributes && t.Hash == html.Colgroup {
 					break
-				} else if t.TokenType == html.EndTagToken {
+				} else if !keepEndTags && t.TokenType == html.EndTagToken {
 					if t.Hash == html.Thead || t.Hash == html.Tbody || t.Hash == html.Tfoot || t.Hash == html.Tr || t.Hash == html.Th || t.Hash == html.Td ||
 						t.Hash == html.Optgroup || t.Hash == html.Option || t.Hash == html.Dd || t.Hash == html.Dt ||
 						t.Hash == html.Li || t.Hash == html.Rb || t.Hash == html.Rt || t.Hash == html.Rtc || t.Hash == html.Rp {
@@ -184,6 +298,11 @@ func Minify(m minify.Minifier, _ string, w io.Writer, r io.Reader) error {
 						remove := false
 						i := 0
 						for {
+							if i >= maxPeek {
+								// give up and conservatively keep the end tag rather than buffer
+								// arbitrarily far ahead looking for a decisive token
+								break
+							}
 							next := tb.Peek(i)
 							i++
 							// continue if text token is empty or whitespace
@@ -215,160 +334,30 @@ func Minify(m minify.Minifier, _ string, w io.Writer, r io.Reader) error {
 			}
 
 			if hasAttributes {
-				// rewrite attributes with interdependent conditions
-				if t.Hash == html.A {
-					if attr := getAttributes(tb, &attrIntBuffer, &attrTokenBuffer, html.Id, html.Name, html.Rel, html.Href); attr != nil {
-						if id := attr[0]; id != nil {
-							if name := attr[1]; name != nil && parse.Equal(id.AttrVal, name.AttrVal) {
-								name.Data = nil
-							}
-						}
-						if rel := attr[2]; rel == nil || !parse.EqualCaseInsensitive(rel.AttrVal, externalBytes) {
-							if href := attr[3]; href != nil {
-								if len(href.AttrVal) > 5 && parse.EqualCaseInsensitive(href.AttrVal[:4], []byte{'h', 't', 't', 'p'}) {
-									if href.AttrVal[4] == ':' {
-										href.AttrVal = href.AttrVal[5:]
-									} else if (href.AttrVal[4] == 's' || href.AttrVal[4] == 'S') && href.AttrVal[5] == ':' {
-										href.AttrVal = href.AttrVal[6:]
-									}
-								}
-							}
-						}
-					}
-				} else if t.Hash == html.Meta {
-					if attr := getAttributes(tb, &attrIntBuffer, &attrTokenBuffer, html.Content, html.Http_Equiv, html.Charset, html.Name); attr != nil {
-						if content := attr[0]; content != nil {
-							if httpEquiv := attr[1]; httpEquiv != nil {
-								content.AttrVal = parse.NormalizeContentType(content.AttrVal)
-								if charset := attr[2]; charset == nil && parse.EqualCaseInsensitive(httpEquiv.AttrVal, []byte("content-type")) && parse.Equal(content.AttrVal, []byte("text/html;charset=utf-8")) {
-									httpEquiv.Data = nil
-									content.Data = []byte("charset")
-									content.Hash = html.Charset
-									content.AttrVal = []byte("utf-8")
-								} else if parse.EqualCaseInsensitive(httpEquiv.AttrVal, []byte("content-style-type")) {
-									defaultStyleType = string(content.AttrVal)
-									defaultInlineStyleType = defaultStyleType + ";inline=1"
-								} else if parse.EqualCaseInsensitive(httpEquiv.AttrVal, []byte("content-script-type")) {
-									defaultScriptType = string(content.AttrVal)
-								}
-							}
-							if name := attr[3]; name != nil {
-								if parse.EqualCaseInsensitive(name.AttrVal, []byte("keywords")) {
-									content.AttrVal = bytes.Replace(content.AttrVal, []byte(", "), []byte(","), -1)
-								} else if parse.EqualCaseInsensitive(name.AttrVal, []byte("viewport")) {
-									content.AttrVal = bytes.Replace(content.AttrVal, []byte(" "), []byte(""), -1)
-								}
-							}
-						}
-					}
-				} else if t.Hash == html.Script {
-					if attr := getAttributes(tb, &attrIntBuffer, &attrTokenBuffer, html.Src, html.Charset); attr != nil {
-						if src := attr[0]; src != nil {
-							if charset := attr[1]; charset != nil {
-								charset.Data = nil
-							}
-						}
-					}
-				}
-
-				// write attributes
-				for {
-					attr := *tb.Shift()
-					if attr.TokenType != html.AttributeToken {
+				attrs := make([]Token, 0, 4)
+				for i := 0; ; i++ {
+					next := tb.Peek(i)
+					if next.TokenType != html.AttributeToken {
 						break
-					} else if attr.Data == nil {
-						continue // removed attribute
-					}
-
-					val := attr.AttrVal
-					if len(val) > 1 && (val[0] == '"' || val[0] == '\'') {
-						val = parse.Trim(val[1:len(val)-1], parse.IsWhitespace)
-					}
-					if len(val) == 0 && (attr.Hash == html.Class ||
-						attr.Hash == html.Dir ||
-						attr.Hash == html.Id ||
-						attr.Hash == html.Lang ||
-						attr.Hash == html.Name ||
-						attr.Hash == html.Style ||
-						attr.Hash == html.Title ||
-						attr.Hash == html.Action && t.Hash == html.Form ||
-						attr.Hash == html.Value && t.Hash == html.Input ||
-						len(attr.Data) > 2 && attr.Data[0] == 'o' && attr.Data[1] == 'n') {
-						continue // omit empty attribute values
-					}
-					if caseInsensitiveAttrMap[attr.Hash] {
-						val = parse.ToLower(val)
-						if attr.Hash == html.Enctype || attr.Hash == html.Codetype || attr.Hash == html.Accept || attr.Hash == html.Type && (t.Hash == html.A || t.Hash == html.Link || t.Hash == html.Object || t.Hash == html.Param || t.Hash == html.Script || t.Hash == html.Style || t.Hash == html.Source) {
-							val = parse.NormalizeContentType(val)
-						}
-					}
-					if rawTag != 0 && attr.Hash == html.Type {
-						rawTagMediatype = val
 					}
+					attrs = append(attrs, *next)
+				}
 
-					// default attribute values can be ommited
-					if attr.Hash == html.Type && (t.Hash == html.Script && parse.Equal(val, []byte("text/javascript")) ||
-						t.Hash == html.Style && parse.Equal(val, []byte("text/css")) ||
-						t.Hash == html.Link && parse.Equal(val, []byte("text/css")) ||
-						t.Hash == html.Input && parse.Equal(val, []byte("text")) ||
-						t.Hash == html.Button && parse.Equal(val, []byte("submit"))) ||
-						attr.Hash == html.Language && t.Hash == html.Script ||
-						attr.Hash == html.Method && parse.Equal(val, []byte("get")) ||
-						attr.Hash == html.Enctype && parse.Equal(val, []byte("application/x-www-form-urlencoded")) ||
-						attr.Hash == html.Colspan && parse.Equal(val, []byte("1")) ||
-						attr.Hash == html.Rowspan && parse.Equal(val, []byte("1")) ||
-						attr.Hash == html.Shape && parse.Equal(val, []byte("rect")) ||
-						attr.Hash == html.Span && parse.Equal(val, []byte("1")) ||
-						attr.Hash == html.Clear && parse.Equal(val, []byte("none")) ||
-						attr.Hash == html.Frameborder && parse.Equal(val, []byte("1")) ||
-						attr.Hash == html.Scrolling && parse.Equal(val, []byte("auto")) ||
-						attr.Hash == html.Valuetype && parse.Equal(val, []byte("data")) ||
-						attr.Hash == html.Media && t.Hash == html.Style && parse.Equal(val, []byte("all")) {
-						continue
-					}
-					if _, err := w.Write(spaceBytes); err != nil {
-						return err
-					}
-					if _, err := w.Write(attr.Data); err != nil {
+				aw.applyInterdependentAttrs(t.Hash, attrs)
+				for _, attr := range attrs {
+					if err := aw.writeAttr(w, t.Hash, attr); err != nil {
 						return err
 					}
-
-					if len(val) > 0 && !booleanAttrMap[attr.Hash] {
-						if _, err := w.Write(isBytes); err != nil {
-							return err
-						}
-						// CSS and JS minifiers for attribute inline code
-						if attr.Hash == html.Style {
-							attrMinifyBuffer.Reset()
-							if m.Minify(defaultInlineStyleType, attrMinifyBuffer, buffer.NewReader(val)) == nil {
-								val = attrMinifyBuffer.Bytes()
-							}
-						} else if len(attr.Data) > 2 && attr.Data[0] == 'o' && attr.Data[1] == 'n' {
-							if len(val) >= 11 && parse.EqualCaseInsensitive(val[:11], []byte("javascript:")) {
-								val = val[11:]
-							}
-							attrMinifyBuffer.Reset()
-							if m.Minify(defaultScriptType, attrMinifyBuffer, buffer.NewReader(val)) == nil {
-								val = attrMinifyBuffer.Bytes()
-							}
-						} else if t.Hash != html.A && urlAttrMap[attr.Hash] { // anchors are already handled
-							if len(val) > 5 && parse.EqualCaseInsensitive(val[:4], []byte{'h', 't', 't', 'p'}) {
-								if val[4] == ':' {
-									val = val[5:]
-								} else if (val[4] == 's' || val[4] == 'S') && val[5] == ':' {
-									val = val[6:]
-								}
-							}
-						}
-						// no quotes if possible, else prefer single or double depending on which occurs more often in value
-						val = escapeAttrVal(&attrByteBuffer, attr.AttrVal, val)
-						if _, err := w.Write(val); err != nil {
-							return err
-						}
-					}
+				}
+				for range attrs {
+					tb.Shift()
 				}
 			}
-			if _, err := w.Write(gtBytes); err != nil {
+			if o.XHTML && t.TokenType == html.StartTagToken && voidTagMap[t.Hash] {
+				if _, err := w.Write(slashGtBytes); err != nil {
+					return err
+				}
+			} else if _, err := w.Write(gtBytes); err != nil {
 				return err
 			}
 		}
@@ -377,38 +366,52 @@ func Minify(m minify.Minifier, _ string, w io.Writer, r io.Reader) error {
 
 ////////////////////////////////////////////////////////////////
 
-func getAttributes(tb *TokenBuffer, attrIndexBuffer *[]int, attrTokenBuffer *[]*Token, hashes ...html.Hash) []*Token {
-	if cap(*attrIndexBuffer) < len(hashes) || cap(*attrTokenBuffer) < len(hashes) {
-		*attrIndexBuffer = make([]int, 0, len(hashes))
-		*attrTokenBuffer = make([]*Token, 0, len(hashes))
+// minifyDataURI minifies the mediatype payload of a data: URI by parsing it
+// with parse.DataURI and recursively invoking m.Minify, then re-encodes the
+// result as either a percent-encoded or base64-encoded data URI, whichever is
+// shorter. It falls back to returning dataURI unchanged when the mediatype
+// has no registered minifier or the URI fails to parse.
+func minifyDataURI(m minify.Minifier, dataURI []byte) []byte {
+	mediatype, data, err := parse.DataURI(dataURI)
+	if err != nil {
+		return dataURI
 	}
-	*attrIndexBuffer = (*attrIndexBuffer)[:len(hashes)]
-	*attrTokenBuffer = (*attrTokenBuffer)[:len(hashes)]
-	i := 0
-	for {
-		t := tb.Peek(i)
-		if t.TokenType != html.AttributeToken {
-			break
-		}
-		for j, hash := range hashes {
-			if t.Hash == hash {
-				(*attrIndexBuffer)[j] = i + 1
-			}
+
+	minifyBuffer := buffer.NewWriter(make([]byte, 0, len(data)))
+	if err := m.Minify(string(mediatype), minifyBuffer, buffer.NewReader(data)); err != nil {
+		return dataURI // no minifier registered for this mediatype (or it failed), leave the URI untouched
+	}
+	data = minifyBuffer.Bytes()
+
+	asciiLen := len(data)
+	for _, c := range data {
+		if !dataURIUnreservedMap[c] {
+			asciiLen += 2
 		}
-		i++
 	}
-	for j, i := range *attrIndexBuffer {
-		if i > 0 {
-			t := tb.Peek(i - 1)
-			if len(t.AttrVal) > 1 && (t.AttrVal[0] == '"' || t.AttrVal[0] == '\'') {
-				t.AttrVal = parse.Trim(t.AttrVal[1:len(t.AttrVal)-1], parse.IsWhitespace) // quotes will be readded in attribute loop if necessary
+	base64Len := len(base64Bytes) + base64.StdEncoding.EncodedLen(len(data))
+
+	if asciiLen < base64Len { // the percent-encoded form also has a ',' separator
+		encoded := make([]byte, 0, len(dataBytes)+len(mediatype)+1+asciiLen)
+		encoded = append(encoded, dataBytes...)
+		encoded = append(encoded, mediatype...)
+		encoded = append(encoded, ',')
+		for _, c := range data {
+			if dataURIUnreservedMap[c] {
+				encoded = append(encoded, c)
+			} else {
+				encoded = append(encoded, '%', "0123456789ABCDEF"[c>>4], "0123456789ABCDEF"[c&0xf])
 			}
-			(*attrTokenBuffer)[j] = t
-		} else {
-			(*attrTokenBuffer)[j] = nil
 		}
+		return encoded
 	}
-	return *attrTokenBuffer
+
+	encoded := make([]byte, len(dataBytes)+len(mediatype)+len(base64Bytes)+base64.StdEncoding.EncodedLen(len(data)))
+	n := copy(encoded, dataBytes)
+	n += copy(encoded[n:], mediatype)
+	n += copy(encoded[n:], base64Bytes)
+	base64.StdEncoding.Encode(encoded[n:], data)
+	return encoded
 }
 
 // it is assumed that b[0] equals '&'
@@ -453,10 +456,11 @@ func isAtQuoteEntity(b []byte) (quote byte, n int, ok bool) {
 }
 
 // escapeAttrVal returns the escaped attribute value bytes without quotes.
-func escapeAttrVal(buf *[]byte, orig, b []byte) []byte {
+// If keepQuotes is true, the value is always wrapped in quotes.
+func escapeAttrVal(buf *[]byte, orig, b []byte, keepQuotes bool) []byte {
 	singles := 0
 	doubles := 0
-	unquoted := true
+	unquoted := !keepQuotes
 	for i, c := range b {
 		if c == '&' {
 			if quote, _, ok := isAtQuoteEntity(b[i:]); ok {
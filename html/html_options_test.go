@@ -0,0 +1,113 @@
+package html
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tdewolff/minify"
+)
+
+func TestMinifierKeepWhitespace(t *testing.T) {
+	m := minify.New()
+	in := `<p>a</p>   <p>b</p>`
+
+	var collapsed bytes.Buffer
+	if err := (&Minifier{}).Minify(m, &collapsed, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(collapsed.Bytes(), []byte("   ")) {
+		t.Errorf("Minify(%q) = %q, want whitespace between tags collapsed", in, collapsed.String())
+	}
+
+	var kept bytes.Buffer
+	if err := (&Minifier{KeepWhitespace: true}).Minify(m, &kept, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(kept.Bytes(), []byte("   ")) {
+		t.Errorf("Minify(%q) with KeepWhitespace = %q, want whitespace between tags preserved", in, kept.String())
+	}
+}
+
+func TestMinifierKeepDefaultAttrVals(t *testing.T) {
+	m := minify.New()
+	in := `<script type="text/javascript"></script>`
+
+	var omitted bytes.Buffer
+	if err := (&Minifier{}).Minify(m, &omitted, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(omitted.Bytes(), []byte("type=")) {
+		t.Errorf("Minify(%q) = %q, want default type attribute omitted", in, omitted.String())
+	}
+
+	var kept bytes.Buffer
+	if err := (&Minifier{KeepDefaultAttrVals: true}).Minify(m, &kept, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(kept.Bytes(), []byte(`type=text/javascript`)) {
+		t.Errorf("Minify(%q) with KeepDefaultAttrVals = %q, want type attribute kept", in, kept.String())
+	}
+}
+
+func TestMinifierKeepDocumentTags(t *testing.T) {
+	m := minify.New()
+	in := `<html><head></head><body>x</body></html>`
+
+	var stripped bytes.Buffer
+	if err := (&Minifier{}).Minify(m, &stripped, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(stripped.Bytes(), []byte("<html>")) {
+		t.Errorf("Minify(%q) = %q, want <html>/<head>/<body> stripped", in, stripped.String())
+	}
+
+	var kept bytes.Buffer
+	if err := (&Minifier{KeepDocumentTags: true}).Minify(m, &kept, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(kept.Bytes(), []byte("<html>")) {
+		t.Errorf("Minify(%q) with KeepDocumentTags = %q, want <html> kept", in, kept.String())
+	}
+}
+
+func TestMinifierKeepEndTags(t *testing.T) {
+	m := minify.New()
+	in := `<ul><li>a</li><li>b</li></ul>`
+
+	var omitted bytes.Buffer
+	if err := (&Minifier{}).Minify(m, &omitted, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(omitted.Bytes(), []byte("</li>")) {
+		t.Errorf("Minify(%q) = %q, want optional </li> end tags omitted", in, omitted.String())
+	}
+
+	var kept bytes.Buffer
+	if err := (&Minifier{KeepEndTags: true}).Minify(m, &kept, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(kept.Bytes(), []byte("</li>")) {
+		t.Errorf("Minify(%q) with KeepEndTags = %q, want </li> end tags kept", in, kept.String())
+	}
+}
+
+func TestMinifierKeepQuotes(t *testing.T) {
+	m := minify.New()
+	in := `<div class="a">x</div>`
+
+	var unquoted bytes.Buffer
+	if err := (&Minifier{}).Minify(m, &unquoted, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(unquoted.Bytes(), []byte(`"`)) {
+		t.Errorf("Minify(%q) = %q, want quotes dropped where safe", in, unquoted.String())
+	}
+
+	var kept bytes.Buffer
+	if err := (&Minifier{KeepQuotes: true}).Minify(m, &kept, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(kept.Bytes(), []byte(`"`)) {
+		t.Errorf("Minify(%q) with KeepQuotes = %q, want quotes kept", in, kept.String())
+	}
+}
@@ -0,0 +1,41 @@
+package html
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/tdewolff/minify"
+)
+
+func TestMinifyDataURI(t *testing.T) {
+	m := minify.New()
+	m.AddFunc("text/css", func(_ minify.Minifier, _ string, w io.Writer, r io.Reader) error {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(bytes.TrimSpace(b))
+		return err
+	})
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		// short ASCII payload stays percent-encoded, it is shorter than base64
+		{`<a href="data:text/css,a{color:red}">x</a>`, `data:text/css,a%7Bcolor%3Ared%7D`},
+		// unregistered mediatype falls back to the original bytes verbatim
+		{`<a href="data:image/png;base64,aGVsbG8=">x</a>`, `data:image/png;base64,aGVsbG8=`},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := DefaultMinifier.Minify(m, &out, bytes.NewBufferString(tt.in)); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(out.Bytes(), []byte(tt.want)) {
+			t.Errorf("Minify(%q) = %q, want to contain %q", tt.in, out.String(), tt.want)
+		}
+	}
+}
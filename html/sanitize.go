@@ -0,0 +1,291 @@
+package html // import "github.com/tdewolff/minify/html"
+
+import (
+	"io"
+
+	"github.com/tdewolff/minify"
+	"github.com/tdewolff/parse"
+	"github.com/tdewolff/parse/html"
+)
+
+var (
+	relBytes         = []byte("rel")
+	noopenerRelBytes = []byte("noopener noreferrer")
+	blankBytes       = []byte("_blank")
+	javascriptBytes  = []byte("javascript")
+)
+
+// dangerousTagMap lists tags that a Policy may never allow, regardless of its
+// AllowedTags configuration, along with their content (for script and style
+// this also means their raw, unparsed text is dropped).
+var dangerousTagMap = map[html.Hash]bool{
+	html.Script: true,
+	html.Style:  true,
+	html.Iframe: true,
+	html.Object: true,
+	html.Embed:  true,
+}
+
+// Policy configures which tags and attributes a sanitizing Minifier lets
+// through the HTML tokenizer pipeline.
+type Policy struct {
+	// AllowedTags maps an allowed tag to the set of attributes permitted on
+	// it. A tag present in dangerousTagMap is dropped along with its
+	// content; any other tag absent here is unwrapped instead, i.e. the tag
+	// itself is dropped but its children are still written.
+	AllowedTags map[html.Hash]map[html.Hash]bool
+
+	// AllowedSchemes lists the lowercase URI schemes permitted in urlAttrMap
+	// attributes (href, src, action, ...). Relative URLs, which have no
+	// scheme, are always allowed. javascript: is always blocked.
+	AllowedSchemes map[string]bool
+
+	// AddNoopener injects rel="noopener noreferrer" onto <a target="_blank">
+	// elements that are allowed through, provided AllowedTags permits rel on
+	// <a>; otherwise no rel attribute is added.
+	AddNoopener bool
+}
+
+// NewDefaultPolicy returns a conservative allowlist covering common text
+// formatting and structural tags, http(s)/mailto/tel links, and no scripting
+// or embedding.
+func NewDefaultPolicy() *Policy {
+	return &Policy{
+		AllowedTags: map[html.Hash]map[html.Hash]bool{
+			html.A:          {html.Href: true, html.Title: true, html.Target: true, html.Rel: true},
+			html.B:          {},
+			html.Strong:     {},
+			html.I:          {},
+			html.Em:         {},
+			html.U:          {},
+			html.P:          {},
+			html.Br:         {},
+			html.Hr:         {},
+			html.Ul:         {},
+			html.Ol:         {},
+			html.Li:         {},
+			html.Blockquote: {},
+			html.Code:       {},
+			html.Pre:        {},
+			html.H1:         {},
+			html.H2:         {},
+			html.H3:         {},
+			html.H4:         {},
+			html.H5:         {},
+			html.H6:         {},
+			html.Img:        {html.Src: true, html.Alt: true, html.Title: true, html.Width: true, html.Height: true},
+			html.Span:       {html.Class: true},
+			html.Div:        {html.Class: true},
+		},
+		AllowedSchemes: map[string]bool{"http": true, "https": true, "mailto": true, "tel": true},
+		AddNoopener:    true,
+	}
+}
+
+// NewSanitizingMinifier returns a minify.MinifierFunc-compatible function
+// that walks the same html.NewTokenizer/TokenBuffer pipeline as Minify, but
+// drops any tag or attribute not allowed by policy instead of writing it out.
+func NewSanitizingMinifier(policy *Policy) func(m minify.Minifier, mediatype string, w io.Writer, r io.Reader) error {
+	return func(m minify.Minifier, _ string, w io.Writer, r io.Reader) error {
+		return policy.Minify(m, w, r)
+	}
+}
+
+// Minify sanitizes and minifies HTML5 files according to the policy, it
+// reads from r and writes to w.
+func (p *Policy) Minify(m minify.Minifier, w io.Writer, r io.Reader) error {
+	var skipTag html.Hash
+	skipDepth := 0
+
+	z := html.NewTokenizer(r)
+	tb := NewTokenBuffer(z)
+	for {
+		t := *tb.Shift()
+		switch t.TokenType {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return nil
+			}
+			return z.Err()
+		case html.TextToken:
+			if skipDepth == 0 {
+				if _, err := w.Write(t.Data); err != nil {
+					return err
+				}
+			}
+		case html.StartTagToken, html.EndTagToken:
+			if skipDepth > 0 {
+				if t.Hash == skipTag {
+					if t.TokenType == html.StartTagToken && !voidTagMap[t.Hash] {
+						skipDepth++
+					} else if t.TokenType == html.EndTagToken {
+						skipDepth--
+					}
+				}
+				p.discardAttributes(tb)
+				break
+			}
+
+			attrs, allowed := p.AllowedTags[t.Hash]
+			if dangerousTagMap[t.Hash] {
+				p.discardAttributes(tb)
+				if t.TokenType == html.StartTagToken && !voidTagMap[t.Hash] {
+					skipTag = t.Hash
+					skipDepth = 1
+				}
+				break
+			}
+			if !allowed {
+				// a disallowed but non-dangerous tag is unwrapped: drop the
+				// tag itself and its attributes, but keep writing its
+				// children instead of pruning the whole subtree
+				p.discardAttributes(tb)
+				break
+			}
+
+			if t.TokenType == html.EndTagToken {
+				if _, err := w.Write(endBytes); err != nil {
+					return err
+				}
+			} else if _, err := w.Write(ltBytes); err != nil {
+				return err
+			}
+			if _, err := w.Write(t.Data); err != nil {
+				return err
+			}
+			if err := p.writeAttributes(w, tb, t.Hash, attrs); err != nil {
+				return err
+			}
+			if _, err := w.Write(gtBytes); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// discardAttributes consumes and drops the AttributeTokens following a start
+// tag without writing anything.
+func (p *Policy) discardAttributes(tb *TokenBuffer) {
+	for {
+		if tb.Peek(0).TokenType != html.AttributeToken {
+			return
+		}
+		tb.Shift()
+	}
+}
+
+// writeAttributes consumes the AttributeTokens following a start tag,
+// writing only those allowed by attrs, validating URI schemes for
+// urlAttrMap attributes, always dropping event-handler attributes, and
+// injecting rel="noopener noreferrer" on <a target="_blank"> when
+// p.AddNoopener is set.
+func (p *Policy) writeAttributes(w io.Writer, tb *TokenBuffer, tagHash html.Hash, attrs map[html.Hash]bool) error {
+	// determine target="_blank" and the presence of rel up front via a
+	// lookahead pass, since the rel-rewriting decision below must not depend
+	// on which of the two attributes the source markup happens to list first
+	isBlankTargetAnchor, hasRel := false, false
+	if tagHash == html.A {
+		isBlankTargetAnchor, hasRel = peekAnchorRelTarget(tb)
+	}
+
+	for {
+		attr := *tb.Shift()
+		if attr.TokenType != html.AttributeToken {
+			break
+		}
+		if !attrs[attr.Hash] {
+			continue
+		}
+		if len(attr.Data) > 2 && attr.Data[0] == 'o' && attr.Data[1] == 'n' {
+			continue // event handlers are never allowed, regardless of policy
+		}
+
+		val := attr.AttrVal
+		if len(val) > 1 && (val[0] == '"' || val[0] == '\'') {
+			val = parse.Trim(val[1:len(val)-1], parse.IsWhitespace)
+		}
+
+		if urlAttrMap[attr.Hash] && !p.schemeAllowed(val) {
+			continue
+		}
+
+		if tagHash == html.A && attr.Hash == html.Rel && p.AddNoopener && isBlankTargetAnchor {
+			val = append(append(append([]byte{}, val...), ' '), noopenerRelBytes...)
+		}
+
+		if _, err := w.Write(spaceBytes); err != nil {
+			return err
+		}
+		if _, err := w.Write(attr.Data); err != nil {
+			return err
+		}
+		if len(val) > 0 && !booleanAttrMap[attr.Hash] {
+			if _, err := w.Write(isBytes); err != nil {
+				return err
+			}
+			val = escapeAttrVal(&[]byte{}, attr.AttrVal, val, false)
+			if _, err := w.Write(val); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.AddNoopener && tagHash == html.A && isBlankTargetAnchor && !hasRel && attrs[html.Rel] {
+		if _, err := w.Write(spaceBytes); err != nil {
+			return err
+		}
+		if _, err := w.Write(relBytes); err != nil {
+			return err
+		}
+		if _, err := w.Write(isBytes); err != nil {
+			return err
+		}
+		val := escapeAttrVal(&[]byte{}, nil, noopenerRelBytes, false)
+		if _, err := w.Write(val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// peekAnchorRelTarget looks ahead over the AttributeTokens of the tag
+// currently being written, without consuming them, to determine whether it
+// has target="_blank" and a rel attribute, regardless of the order in which
+// they appear in the source markup.
+func peekAnchorRelTarget(tb *TokenBuffer) (isBlank, hasRel bool) {
+	for i := 0; ; i++ {
+		t := tb.Peek(i)
+		if t.TokenType != html.AttributeToken {
+			return
+		}
+		if t.Hash == html.Target {
+			val := t.AttrVal
+			if len(val) > 1 && (val[0] == '"' || val[0] == '\'') {
+				val = parse.Trim(val[1:len(val)-1], parse.IsWhitespace)
+			}
+			if parse.EqualCaseInsensitive(val, blankBytes) {
+				isBlank = true
+			}
+		} else if t.Hash == html.Rel {
+			hasRel = true
+		}
+	}
+}
+
+// schemeAllowed reports whether val, the value of a urlAttrMap attribute,
+// uses either no scheme (a relative URL) or one of p.AllowedSchemes.
+// javascript: URIs are always rejected.
+func (p *Policy) schemeAllowed(val []byte) bool {
+	i := 0
+	for i < len(val) && val[i] != ':' && val[i] != '/' && val[i] != '?' && val[i] != '#' {
+		i++
+	}
+	if i == len(val) || val[i] != ':' {
+		return true // relative URL, no scheme
+	}
+	scheme := parse.ToLower(append([]byte{}, val[:i]...))
+	if parse.EqualCaseInsensitive(scheme, javascriptBytes) {
+		return false
+	}
+	return p.AllowedSchemes[string(scheme)]
+}
@@ -0,0 +1,98 @@
+package html
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tdewolff/minify"
+)
+
+func TestPolicyNoopenerAttributeOrder(t *testing.T) {
+	m := minify.New()
+	policy := NewDefaultPolicy()
+
+	// rel before target and target before rel must both end up with
+	// noopener noreferrer appended, since the decision must not depend on
+	// which of the two attributes the source markup lists first
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"rel before target", `<a href="https://example.com" rel="nofollow" target="_blank">x</a>`},
+		{"target before rel", `<a href="https://example.com" target="_blank" rel="nofollow">x</a>`},
+		{"target only", `<a href="https://example.com" target="_blank">x</a>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := policy.Minify(m, &out, bytes.NewBufferString(tt.in)); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Contains(out.Bytes(), []byte("noopener noreferrer")) {
+				t.Errorf("Minify(%q) = %q, want rel to contain noopener noreferrer", tt.in, out.String())
+			}
+		})
+	}
+}
+
+func TestPolicySchemeAllowlist(t *testing.T) {
+	m := minify.New()
+	policy := NewDefaultPolicy()
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`<a href="https://example.com">x</a>`, `href="https://example.com"`},
+		{`<a href="javascript:alert(1)">x</a>`, ``},
+		{`<a href="ftp://example.com/f">x</a>`, ``},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := policy.Minify(m, &out, bytes.NewBufferString(tt.in)); err != nil {
+			t.Fatal(err)
+		}
+		if tt.want != "" && !bytes.Contains(out.Bytes(), []byte(tt.want)) {
+			t.Errorf("Minify(%q) = %q, want to contain %q", tt.in, out.String(), tt.want)
+		}
+		if tt.want == "" && bytes.Contains(out.Bytes(), []byte("href=")) {
+			t.Errorf("Minify(%q) = %q, want href stripped", tt.in, out.String())
+		}
+	}
+}
+
+func TestPolicyDisallowedTagUnwrapped(t *testing.T) {
+	m := minify.New()
+	policy := NewDefaultPolicy()
+
+	in := `<p>keep <font color="red">Hello <b>World</b></font></p>`
+	var out bytes.Buffer
+	if err := policy.Minify(m, &out, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	if bytes.Contains(out.Bytes(), []byte("font")) {
+		t.Errorf("Minify(%q) = %q, want <font> tag dropped", in, got)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("Hello")) || !bytes.Contains(out.Bytes(), []byte("<b>World</b>")) {
+		t.Errorf("Minify(%q) = %q, want disallowed tag's children kept", in, got)
+	}
+}
+
+func TestPolicyDangerousTagsDropped(t *testing.T) {
+	m := minify.New()
+	policy := NewDefaultPolicy()
+
+	in := `<p>keep</p><script>alert(1)</script><iframe src="x"></iframe>`
+	var out bytes.Buffer
+	if err := policy.Minify(m, &out, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	if !bytes.Contains(out.Bytes(), []byte("<p>keep</p>")) {
+		t.Errorf("Minify(%q) = %q, want <p>keep</p> preserved", in, got)
+	}
+	if bytes.Contains(out.Bytes(), []byte("script")) || bytes.Contains(out.Bytes(), []byte("iframe")) {
+		t.Errorf("Minify(%q) = %q, want script/iframe and their content dropped", in, got)
+	}
+}
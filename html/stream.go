@@ -0,0 +1,226 @@
+package html // import "github.com/tdewolff/minify/html"
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/tdewolff/buffer"
+	"github.com/tdewolff/minify"
+	"github.com/tdewolff/parse"
+	"github.com/tdewolff/parse/html"
+)
+
+// StreamWriter incrementally minifies HTML5 tokens supplied one at a time by
+// a caller-owned tokenizer, writing each to w as it is fed, rather than
+// buffering the remaining input the way Minify's TokenBuffer does. Because it
+// never looks ahead, it always takes the conservative choice at decision
+// points Minify would otherwise resolve by peeking: it keeps inter-tag
+// whitespace and optional end tags rather than risk holding back output
+// waiting on tokens that may never come.
+type StreamWriter struct {
+	o *Minifier
+	m minify.Minifier
+	w io.Writer
+
+	rawTag                 html.Hash
+	rawTagMediatype        []byte
+	defaultScriptType      string
+	defaultStyleType       string
+	defaultInlineStyleType string
+
+	attrMinifyBuffer *buffer.Writer
+	attrByteBuffer   []byte
+
+	openTag    Token
+	openAttrs  []Token
+	hasOpenTag bool
+}
+
+// NewStreamWriter returns a StreamWriter that writes minified HTML to w,
+// using m to minify inline CSS and JS, and the options set on o.
+func (o *Minifier) NewStreamWriter(m minify.Minifier, w io.Writer) *StreamWriter {
+	return &StreamWriter{
+		o: o,
+		m: m,
+		w: w,
+
+		defaultScriptType:      "text/javascript",
+		defaultStyleType:       "text/css",
+		defaultInlineStyleType: "text/css;inline=1",
+
+		attrMinifyBuffer: buffer.NewWriter(make([]byte, 0, 64)),
+		attrByteBuffer:   make([]byte, 0, 64),
+	}
+}
+
+// WriteToken minifies a single token and writes the result to the
+// StreamWriter's writer. Tokens must be supplied in document order, as
+// produced by a caller-owned html.Tokenizer.
+func (s *StreamWriter) WriteToken(t Token) error {
+	switch t.TokenType {
+	case html.AttributeToken:
+		if s.hasOpenTag {
+			s.openAttrs = append(s.openAttrs, t)
+			return nil
+		}
+		return nil // stray attribute token without an open tag, ignore
+	case html.DoctypeToken:
+		if err := s.flushOpenTag(); err != nil {
+			return err
+		}
+		_, err := s.w.Write([]byte("<!doctype html>"))
+		return err
+	case html.CommentToken:
+		if err := s.flushOpenTag(); err != nil {
+			return err
+		}
+		return s.writeComment(t)
+	case html.TextToken:
+		if err := s.flushOpenTag(); err != nil {
+			return err
+		}
+		return s.writeText(t)
+	case html.StartTagToken:
+		if err := s.flushOpenTag(); err != nil {
+			return err
+		}
+		s.openTag = t
+		s.openAttrs = s.openAttrs[:0]
+		s.hasOpenTag = true
+		return nil
+	case html.EndTagToken:
+		if err := s.flushOpenTag(); err != nil {
+			return err
+		}
+		s.rawTag = 0
+		return s.writeTag(t, nil)
+	}
+	return nil
+}
+
+// Close flushes any start tag withheld while waiting for its attributes.
+// Call it after feeding the final token of the document.
+func (s *StreamWriter) Close() error {
+	return s.flushOpenTag()
+}
+
+func (s *StreamWriter) flushOpenTag() error {
+	if !s.hasOpenTag {
+		return nil
+	}
+	t := s.openTag
+	attrs := s.openAttrs
+	s.hasOpenTag = false
+	s.openAttrs = nil
+
+	s.rawTag = 0
+	if rawTagMap[t.Hash] {
+		s.rawTag = t.Hash
+		s.rawTagMediatype = []byte{}
+	}
+	return s.writeTag(t, attrs)
+}
+
+func (s *StreamWriter) writeComment(t Token) error {
+	var comment []byte
+	if s.o.KeepConditionalComments && bytes.HasPrefix(t.Data, []byte("[if")) {
+		comment = append(append([]byte("<!--"), t.Data...), []byte("-->")...)
+	} else if s.o.KeepConditionalComments && bytes.HasSuffix(t.Data, []byte("--")) {
+		comment = append(append([]byte("<!"), t.Data...), '>')
+	}
+	_, err := s.w.Write(comment)
+	return err
+}
+
+func (s *StreamWriter) writeText(t Token) error {
+	if s.rawTag != 0 {
+		if s.rawTag == html.Style || s.rawTag == html.Script || s.rawTag == html.Iframe || s.rawTag == html.Svg || s.rawTag == html.Math {
+			var mediatype string
+			if s.rawTag == html.Iframe {
+				mediatype = "text/html"
+			} else if len(s.rawTagMediatype) > 0 {
+				mediatype = string(s.rawTagMediatype)
+			} else if s.rawTag == html.Script {
+				mediatype = s.defaultScriptType
+			} else if s.rawTag == html.Style {
+				mediatype = s.defaultStyleType
+			} else if s.rawTag == html.Svg {
+				mediatype = "image/svg+xml"
+			} else if s.rawTag == html.Math {
+				mediatype = "application/mathml+xml"
+			}
+			// in XHTML mode wrap script and style in a CDATA section so the raw text stays well-formed XML
+			var cdataOpen, cdataClose []byte
+			if s.o.XHTML && s.rawTag == html.Script {
+				cdataOpen, cdataClose = scriptCDATAOpenBytes, scriptCDATACloseBytes
+			} else if s.o.XHTML && s.rawTag == html.Style {
+				cdataOpen, cdataClose = styleCDATAOpenBytes, styleCDATACloseBytes
+			}
+			if _, err := s.w.Write(cdataOpen); err != nil {
+				return err
+			}
+			if err := s.m.Minify(mediatype, s.w, buffer.NewReader(t.Data)); err != nil {
+				if err != minify.ErrNotExist {
+					return err
+				}
+				if _, err := s.w.Write(t.Data); err != nil {
+					return err
+				}
+			}
+			_, err := s.w.Write(cdataClose)
+			return err
+		}
+		_, err := s.w.Write(t.Data)
+		return err
+	}
+
+	if s.o.KeepWhitespace && parse.IsAllWhitespace(t.Data) {
+		// whitespace between tags is written as is
+		_, err := s.w.Write(t.Data)
+		return err
+	}
+
+	// no lookahead available: collapse whitespace runs but never trim
+	// across a token boundary
+	t.Data = parse.ReplaceMultiple(t.Data, parse.IsWhitespace, ' ')
+	if len(t.Data) == 0 {
+		return nil
+	}
+	_, err := s.w.Write(t.Data)
+	return err
+}
+
+// writeTag writes a start or end tag along with any attributes already
+// collected for it. attrs is nil for end tags.
+func (s *StreamWriter) writeTag(t Token, attrs []Token) error {
+	if t.TokenType == html.EndTagToken {
+		if _, err := s.w.Write(endBytes); err != nil {
+			return err
+		}
+	} else if _, err := s.w.Write(ltBytes); err != nil {
+		return err
+	}
+	if _, err := s.w.Write(t.Data); err != nil {
+		return err
+	}
+
+	aw := &attrWriter{
+		o: s.o, m: s.m,
+		rawTag: &s.rawTag, rawTagMediatype: &s.rawTagMediatype,
+		defaultScriptType: &s.defaultScriptType, defaultStyleType: &s.defaultStyleType, defaultInlineStyleType: &s.defaultInlineStyleType,
+		attrMinifyBuffer: s.attrMinifyBuffer, attrByteBuffer: &s.attrByteBuffer,
+	}
+	aw.applyInterdependentAttrs(t.Hash, attrs)
+	for _, attr := range attrs {
+		if err := aw.writeAttr(s.w, t.Hash, attr); err != nil {
+			return err
+		}
+	}
+
+	if s.o.XHTML && t.TokenType == html.StartTagToken && voidTagMap[t.Hash] {
+		_, err := s.w.Write(slashGtBytes)
+		return err
+	}
+	_, err := s.w.Write(gtBytes)
+	return err
+}
@@ -0,0 +1,75 @@
+package html
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tdewolff/minify"
+	"github.com/tdewolff/parse/html"
+)
+
+// streamMinify feeds r through a tokenizer token by token into a
+// StreamWriter, mirroring how a caller with its own tokenizer would drive it.
+func streamMinify(o *Minifier, m minify.Minifier, r *bytes.Buffer) (string, error) {
+	var out bytes.Buffer
+	sw := o.NewStreamWriter(m, &out)
+	z := html.NewTokenizer(r)
+	tb := NewTokenBuffer(z)
+	for {
+		t := *tb.Shift()
+		if t.TokenType == html.ErrorToken {
+			break
+		}
+		if err := sw.WriteToken(t); err != nil {
+			return "", err
+		}
+	}
+	if err := sw.Close(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func TestStreamWriterMatchesMinify(t *testing.T) {
+	m := minify.New()
+	o := &Minifier{}
+
+	tests := []string{
+		`<a id="x" name="x" href="http://example.com">y</a>`,
+		`<script type="text/javascript"></script><b>a   b</b>`,
+		`<input disabled>`,
+	}
+	for _, in := range tests {
+		var want bytes.Buffer
+		if err := o.Minify(m, &want, bytes.NewBufferString(in)); err != nil {
+			t.Fatal(err)
+		}
+		got, err := streamMinify(o, m, bytes.NewBufferString(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want.String() {
+			t.Errorf("streamed Minify(%q) = %q, want %q (same as Minify)", in, got, want.String())
+		}
+	}
+}
+
+func TestMinifierMaxPeek(t *testing.T) {
+	m := minify.New()
+
+	in := `<p>a </p><div>b</div>`
+	var unbounded bytes.Buffer
+	if err := (&Minifier{}).Minify(m, &unbounded, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a MaxPeek of 1 still terminates and produces valid output; it only
+	// bounds how far ahead the whitespace/end-tag decisions look
+	var bounded bytes.Buffer
+	if err := (&Minifier{MaxPeek: 1}).Minify(m, &bounded, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+	if bounded.Len() == 0 {
+		t.Errorf("Minify(%q) with MaxPeek: 1 produced no output", in)
+	}
+}
@@ -0,0 +1,53 @@
+package html
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tdewolff/minify"
+)
+
+func TestMinifierXHTML(t *testing.T) {
+	m := minify.New()
+	o := &Minifier{XHTML: true}
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"void self-closing", `<br>`, `<br/>`},
+		{"forced quoting", `<div class=a>x</div>`, `<div class="a">x</div>`},
+		{"boolean attr value", `<input disabled>`, `disabled="disabled"`},
+		{"script CDATA wrapping", `<script>a&&b</script>`, `<![CDATA[`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := o.Minify(m, &out, bytes.NewBufferString(tt.in)); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Contains(out.Bytes(), []byte(tt.want)) {
+				t.Errorf("Minify(%q) = %q, want to contain %q", tt.in, out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMinifierXHTMLImpliesRoundTripOptions(t *testing.T) {
+	m := minify.New()
+	o := &Minifier{XHTML: true}
+
+	in := `<html><head></head><body><ul><li>a</li></ul></body></html>`
+	var out bytes.Buffer
+	if err := o.Minify(m, &out, bytes.NewBufferString(in)); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	if !bytes.Contains(out.Bytes(), []byte("<html>")) {
+		t.Errorf("Minify(%q) with XHTML = %q, want document tags kept", in, got)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("</li>")) {
+		t.Errorf("Minify(%q) with XHTML = %q, want optional end tags kept", in, got)
+	}
+}